@@ -2,6 +2,7 @@ package gdbserial
 
 import (
 	"bufio"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -16,11 +17,85 @@ import (
 	"github.com/go-delve/delve/pkg/proc"
 )
 
+// RecordOptions configures the optional rr record-side flags that are
+// useful when trying to reproduce concurrency bugs, plus where to put the
+// resulting trace.
+type RecordOptions struct {
+	// Chaos enables rr's chaos mode (--chaos), which randomizes scheduling
+	// decisions to make it easier to reproduce races that depend on thread
+	// interleaving.
+	Chaos bool
+
+	// NumCores limits the number of cores rr's chaos mode scheduler is
+	// allowed to use (--num-cores=N). Ignored if zero.
+	NumCores int
+
+	// DisableCPUIDFeaturesExt disables rr's extended CPUID feature set
+	// (--disable-cpuid-features-ext), which can be necessary to record on
+	// some CPUs.
+	DisableCPUIDFeaturesExt bool
+
+	// TraceDir, if non-empty, is passed to rr as the output directory for
+	// the recording (via the _RR_TRACE_DIR environment variable) instead
+	// of rr's default trace directory.
+	TraceDir string
+
+	// Env specifies additional environment variables, on top of the
+	// current process' environment, that the recorded program should see.
+	// A nil value unsets the named variable.
+	Env map[string]*string
+}
+
+// args returns the rr command line arguments corresponding to opts.
+func (opts RecordOptions) args() []string {
+	var args []string
+	if opts.Chaos {
+		args = append(args, "--chaos")
+	}
+	if opts.NumCores > 0 {
+		args = append(args, fmt.Sprintf("--num-cores=%d", opts.NumCores))
+	}
+	if opts.DisableCPUIDFeaturesExt {
+		args = append(args, "--disable-cpuid-features-ext")
+	}
+	return args
+}
+
+// buildEnv returns the environment rr (and the program it records) should
+// run with: the current process' environment, with opts.TraceDir applied
+// as _RR_TRACE_DIR and opts.Env's overrides/unsets layered on top.
+func buildEnv(opts RecordOptions) []string {
+	vars := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			vars[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	if opts.TraceDir != "" {
+		vars["_RR_TRACE_DIR"] = opts.TraceDir
+	}
+
+	for k, v := range opts.Env {
+		if v == nil {
+			delete(vars, k)
+			continue
+		}
+		vars[k] = *v
+	}
+
+	env := make([]string, 0, len(vars))
+	for k, v := range vars {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
 // RecordAsync configures rr to record the execution of the specified
 // program. Returns a run function which will actually record the program, a
 // stop function which will prematurely terminate the recording of the
 // program.
-func RecordAsync(cmd []string, wd string, quiet bool, redirects proc.Redirect) (run func() (string, error), stop func() error, err error) {
+func RecordAsync(cmd []string, wd string, quiet bool, redirects proc.Redirect, opts RecordOptions) (run func() (string, error), stop func() error, err error) {
 	if err := checkRRAvailable(); err != nil {
 		return nil, nil, err
 	}
@@ -32,6 +107,7 @@ func RecordAsync(cmd []string, wd string, quiet bool, redirects proc.Redirect) (
 
 	args := make([]string, 0, len(cmd)+2)
 	args = append(args, "record", "--print-trace-dir=3")
+	args = append(args, opts.args()...)
 	args = append(args, cmd...)
 	rrcmd := exec.Command("rr", args...)
 	var closefn func()
@@ -41,6 +117,9 @@ func RecordAsync(cmd []string, wd string, quiet bool, redirects proc.Redirect) (
 	}
 	rrcmd.ExtraFiles = []*os.File{wfd}
 	rrcmd.Dir = wd
+	if opts.TraceDir != "" || len(opts.Env) > 0 {
+		rrcmd.Env = buildEnv(opts)
+	}
 
 	tracedirChan := make(chan string)
 	go func() {
@@ -121,9 +200,11 @@ func openRedirects(redirects proc.Redirect, quiet bool) (stdin, stdout, stderr *
 }
 
 // Record uses rr to record the execution of the specified program and
-// returns the trace directory's path.
-func Record(cmd []string, wd string, quiet bool, redirects proc.Redirect) (tracedir string, err error) {
-	run, _, err := RecordAsync(cmd, wd, quiet, redirects)
+// returns the trace directory's path. Pass RecordOptions{} for the
+// previous, no-extra-flags behavior; every caller of Record/RecordAsync
+// must be updated to supply an opts argument.
+func Record(cmd []string, wd string, quiet bool, redirects proc.Redirect, opts RecordOptions) (tracedir string, err error) {
+	run, _, err := RecordAsync(cmd, wd, quiet, redirects, opts)
 	if err != nil {
 		return "", err
 	}
@@ -178,6 +259,119 @@ func Replay(tracedir string, quiet, deleteOnDetach bool, debugInfoDirs []string)
 	return tgt, nil
 }
 
+// Bookmark identifies an exact point in an rr replay, using the event and
+// tick coordinates rr itself uses to address a recording.
+type Bookmark struct {
+	// Event is rr's event count at this point in the replay, as reported
+	// by the `when` monitor command.
+	Event uint64
+	// Ticks is rr's retired-conditional-branch tick count at this point
+	// in the replay, as reported by the `when-ticks` monitor command.
+	Ticks uint64
+	// ThreadID is the thread that was current when the bookmark was
+	// taken.
+	ThreadID int
+}
+
+// ReplayTarget is implemented by replay targets that support rr's
+// event/tick timeline, i.e. those returned by Replay. Use a type assertion
+// on the proc.TargetGroup's selected target to obtain it.
+type ReplayTarget interface {
+	// CurrentBookmark returns a Bookmark for the current position in the
+	// replay.
+	CurrentBookmark() (Bookmark, error)
+	// SeekBookmark moves the replay to the position described by bookmark.
+	SeekBookmark(bookmark Bookmark) error
+	// ListCheckpoints returns the bookmarks saved so far through
+	// CurrentBookmark; rr itself is not queried for checkpoints.
+	ListCheckpoints() ([]Bookmark, error)
+}
+
+// CurrentBookmark returns a Bookmark describing the current position of
+// the replay, and records it in p.bookmarks so it is included in a later
+// ListCheckpoints call.
+func (p *gdbProcess) CurrentBookmark() (Bookmark, error) {
+	when, err := p.qRRCmd("when")
+	if err != nil {
+		return Bookmark{}, err
+	}
+	event, err := strconv.ParseUint(strings.TrimSpace(when), 10, 64)
+	if err != nil {
+		return Bookmark{}, fmt.Errorf("could not parse rr event %q: %v", when, err)
+	}
+
+	whenTicks, err := p.qRRCmd("when-ticks")
+	if err != nil {
+		return Bookmark{}, err
+	}
+	ticks, err := strconv.ParseUint(strings.TrimSpace(whenTicks), 10, 64)
+	if err != nil {
+		return Bookmark{}, fmt.Errorf("could not parse rr ticks %q: %v", whenTicks, err)
+	}
+
+	bookmark := Bookmark{Event: event, Ticks: ticks, ThreadID: p.currentThread.ID}
+	p.bookmarks = append(p.bookmarks, bookmark)
+	return bookmark, nil
+}
+
+// SeekBookmark moves the replay to the position described by bookmark.
+// It first uses seek-event to coarsely position the recording at
+// bookmark.Event, then seek-ticks to refine that position down to the
+// exact retired-conditional-branch count recorded in bookmark.Ticks.
+func (p *gdbProcess) SeekBookmark(bookmark Bookmark) error {
+	if _, err := p.qRRCmd(fmt.Sprintf("seek-event %d", bookmark.Event)); err != nil {
+		return err
+	}
+	_, err := p.qRRCmd(fmt.Sprintf("seek-ticks %d", bookmark.Ticks))
+	return err
+}
+
+// ListCheckpoints returns the bookmarks saved so far via CurrentBookmark.
+//
+// rr has no monitor command to enumerate checkpoints over the wire:
+// checkpoints are a gdb-frontend concept managed by gdb's own
+// `checkpoint`/`restart` commands, not something the rr server tracks or
+// exposes. This package therefore maintains its own list of bookmarks
+// instead of querying rr for one.
+func (p *gdbProcess) ListCheckpoints() ([]Bookmark, error) {
+	return append([]Bookmark{}, p.bookmarks...), nil
+}
+
+// qRRCmd sends cmd as an rr monitor command and returns its combined
+// console output. rr's custom commands are issued through gdb's generic
+// monitor-command mechanism: the command text (e.g. "when", "seek-ticks
+// 123") is hex-encoded into a "qRcmd,<hex>" packet, and the reply is zero
+// or more "O<hex>" console-output packets followed by a terminating
+// OK/E/empty reply.
+func (p *gdbProcess) qRRCmd(cmd string) (string, error) {
+	pkt := "qRcmd," + hex.EncodeToString([]byte(cmd))
+	if err := p.conn.send(pkt); err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	for {
+		resp, err := p.conn.readpacket()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case resp == "OK" || resp == "":
+			return out.String(), nil
+		case strings.HasPrefix(resp, "E"):
+			return "", fmt.Errorf("rr monitor command %q failed: %s", cmd, resp)
+		case strings.HasPrefix(resp, "O"):
+			decoded, err := hex.DecodeString(resp[1:])
+			if err != nil {
+				return "", fmt.Errorf("malformed rr monitor reply %q: %v", resp, err)
+			}
+			out.Write(decoded)
+		default:
+			return "", fmt.Errorf("unexpected rr monitor reply %q", resp)
+		}
+	}
+}
+
 // ErrPerfEventParanoid is the error returned by Reply and Record if
 // /proc/sys/kernel/perf_event_paranoid is greater than 1.
 type ErrPerfEventParanoid struct {
@@ -289,8 +483,8 @@ func rrParseGdbCommand(line string) rrInit {
 }
 
 // RecordAndReplay acts like calling Record and then Replay.
-func RecordAndReplay(cmd []string, wd string, quiet bool, debugInfoDirs []string, redirects proc.Redirect) (*proc.TargetGroup, string, error) {
-	tracedir, err := Record(cmd, wd, quiet, redirects)
+func RecordAndReplay(cmd []string, wd string, quiet bool, debugInfoDirs []string, redirects proc.Redirect, opts RecordOptions) (*proc.TargetGroup, string, error) {
+	tracedir, err := Record(cmd, wd, quiet, redirects, opts)
 	if tracedir == "" {
 		return nil, "", err
 	}
@@ -298,6 +492,112 @@ func RecordAndReplay(cmd []string, wd string, quiet bool, debugInfoDirs []string
 	return t, tracedir, err
 }
 
+// PackTrace packs the rr trace directory tracedir into a portable trace
+// pack at destPath using `rr pack`, so that it can be copied to and
+// replayed on another machine.
+func PackTrace(tracedir, destPath string) error {
+	if err := checkRRAvailable(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(destPath, 0755); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("rr", "pack", tracedir)
+	cmd.Dir = tracedir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rr pack failed: %v\n%s", err, out)
+	}
+
+	return copyDir(tracedir, destPath)
+}
+
+// UnpackTrace unpacks the trace pack found at tracePackPath (a directory
+// or tarball produced by PackTrace/`rr pack`) into a fresh trace directory
+// and returns its path.
+func UnpackTrace(tracePackPath string) (tracedir string, err error) {
+	if err := checkRRAvailable(); err != nil {
+		return "", err
+	}
+
+	tracedir, err = ioutil.TempDir("", "dlv-rr-trace-")
+	if err != nil {
+		return "", err
+	}
+
+	fi, err := os.Stat(tracePackPath)
+	if err != nil {
+		os.RemoveAll(tracedir)
+		return "", err
+	}
+
+	if fi.IsDir() {
+		if err := copyDir(tracePackPath, tracedir); err != nil {
+			os.RemoveAll(tracedir)
+			return "", err
+		}
+		return tracedir, nil
+	}
+
+	cmd := exec.Command("tar", "-xf", tracePackPath, "-C", tracedir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(tracedir)
+		return "", fmt.Errorf("could not extract trace pack %s: %v\n%s", tracePackPath, err, out)
+	}
+
+	return tracedir, nil
+}
+
+// copyDir copies the contents of src into dst, which must already exist.
+func copyDir(src, dst string) error {
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		srcPath := filepath.Join(src, entry.Name())
+		dstPath := filepath.Join(dst, entry.Name())
+		if entry.IsDir() {
+			if err := os.MkdirAll(dstPath, entry.Mode()); err != nil {
+				return err
+			}
+			if err := copyDir(srcPath, dstPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := copyFile(srcPath, dstPath, entry.Mode()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile streams src to dst, which is created with the given mode.
+// rr trace directories can contain very large data files, so this avoids
+// reading an entire file into memory the way ioutil.ReadFile/WriteFile
+// would.
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
 // safeRemoveAll removes dir and its contents but only as long as dir does
 // not contain directories.
 func safeRemoveAll(dir string) {
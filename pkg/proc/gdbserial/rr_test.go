@@ -0,0 +1,106 @@
+package gdbserial
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecordOptionsArgs(t *testing.T) {
+	tests := []struct {
+		opts RecordOptions
+		want []string
+	}{
+		{RecordOptions{}, nil},
+		{RecordOptions{Chaos: true}, []string{"--chaos"}},
+		{RecordOptions{NumCores: 4}, []string{"--num-cores=4"}},
+		{RecordOptions{DisableCPUIDFeaturesExt: true}, []string{"--disable-cpuid-features-ext"}},
+		{
+			RecordOptions{Chaos: true, NumCores: 2, DisableCPUIDFeaturesExt: true, TraceDir: "/tmp/trace"},
+			[]string{"--chaos", "--num-cores=2", "--disable-cpuid-features-ext"},
+		},
+	}
+
+	for _, test := range tests {
+		got := test.opts.args()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("RecordOptions(%+v).args() = %v, want %v", test.opts, got, test.want)
+		}
+	}
+}
+
+func TestBuildEnv(t *testing.T) {
+	os.Setenv("DLV_RR_TEST_KEEP", "keep")
+	os.Setenv("DLV_RR_TEST_UNSET", "unset-me")
+	defer os.Unsetenv("DLV_RR_TEST_KEEP")
+	defer os.Unsetenv("DLV_RR_TEST_UNSET")
+
+	unset := (*string)(nil)
+	overridden := "overridden"
+	env := buildEnv(RecordOptions{
+		TraceDir: "/tmp/trace",
+		Env: map[string]*string{
+			"DLV_RR_TEST_UNSET": unset,
+			"DLV_RR_TEST_NEW":   &overridden,
+		},
+	})
+
+	got := make(map[string]string)
+	for _, kv := range env {
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			got[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	if got["_RR_TRACE_DIR"] != "/tmp/trace" {
+		t.Errorf("_RR_TRACE_DIR = %q, want %q", got["_RR_TRACE_DIR"], "/tmp/trace")
+	}
+	if got["DLV_RR_TEST_KEEP"] != "keep" {
+		t.Errorf("DLV_RR_TEST_KEEP = %q, want %q", got["DLV_RR_TEST_KEEP"], "keep")
+	}
+	if got["DLV_RR_TEST_NEW"] != "overridden" {
+		t.Errorf("DLV_RR_TEST_NEW = %q, want %q", got["DLV_RR_TEST_NEW"], "overridden")
+	}
+	if _, ok := got["DLV_RR_TEST_UNSET"]; ok {
+		t.Errorf("DLV_RR_TEST_UNSET should have been unset, got %q", got["DLV_RR_TEST_UNSET"])
+	}
+}
+
+func TestCopyDir(t *testing.T) {
+	src, err := ioutil.TempDir("", "dlv-copydir-src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	dst, err := ioutil.TempDir("", "dlv-copydir-dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dst)
+
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyDir(src, dst); err != nil {
+		t.Fatalf("copyDir: %v", err)
+	}
+
+	got, err := ioutil.ReadFile(filepath.Join(dst, "a.txt"))
+	if err != nil || string(got) != "hello" {
+		t.Errorf("a.txt = %q, %v, want %q, nil", got, err, "hello")
+	}
+	got, err = ioutil.ReadFile(filepath.Join(dst, "sub", "b.txt"))
+	if err != nil || string(got) != "world" {
+		t.Errorf("sub/b.txt = %q, %v, want %q, nil", got, err, "world")
+	}
+}
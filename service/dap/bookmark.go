@@ -0,0 +1,52 @@
+package dap
+
+import (
+	"errors"
+
+	"github.com/go-delve/delve/pkg/proc/gdbserial"
+)
+
+// errNotReplaying is returned by the dlvBookmark/dlvSeekBookmark custom
+// requests when the current session is not replaying an rr trace.
+var errNotReplaying = errors.New("this request is only supported when replaying an rr trace")
+
+// onDlvBookmarkRequest handles the custom "dlvBookmark" request: it reports
+// the current position of a replay session (rr's event and tick cursor) so
+// that an editor extension can offer a "copy timeline position" action.
+func (s *Server) onDlvBookmarkRequest(args DlvBookmarkArguments) (DlvBookmarkResponseBody, error) {
+	rt, ok := s.replayTarget()
+	if !ok {
+		return DlvBookmarkResponseBody{}, errNotReplaying
+	}
+
+	bookmark, err := rt.CurrentBookmark()
+	if err != nil {
+		return DlvBookmarkResponseBody{}, err
+	}
+
+	return DlvBookmarkResponseBody{
+		Event:    bookmark.Event,
+		Ticks:    bookmark.Ticks,
+		ThreadID: bookmark.ThreadID,
+	}, nil
+}
+
+// onDlvSeekBookmarkRequest handles the custom "dlvSeekBookmark" request: it
+// moves a replay session back to a previously saved bookmark, so that an
+// editor extension can offer a "jump back to saved point" action.
+func (s *Server) onDlvSeekBookmarkRequest(args DlvSeekBookmarkArguments) error {
+	rt, ok := s.replayTarget()
+	if !ok {
+		return errNotReplaying
+	}
+
+	return rt.SeekBookmark(gdbserial.Bookmark{Event: args.Event, Ticks: args.Ticks})
+}
+
+// replayTarget returns the current target's gdbserial.ReplayTarget view, if
+// the target supports rr's event/tick timeline (i.e. it came from a
+// "replay" or "recordAndReplay" launch).
+func (s *Server) replayTarget() (gdbserial.ReplayTarget, bool) {
+	rt, ok := s.debugger.Target().(gdbserial.ReplayTarget)
+	return rt, ok
+}
@@ -0,0 +1,33 @@
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// onCustomRequest dispatches a DAP custom request to its handler. Only the
+// "dlvBookmark" and "dlvSeekBookmark" cases added for rr replay bookmarks
+// live in this file; other custom requests are handled elsewhere in this
+// package.
+func (s *Server) onCustomRequest(command string, arguments json.RawMessage) (interface{}, error) {
+	switch command {
+	case "dlvBookmark":
+		var args DlvBookmarkArguments
+		if len(arguments) > 0 {
+			if err := unmarshalLaunchAttachArgs(arguments, &args); err != nil {
+				return nil, err
+			}
+		}
+		return s.onDlvBookmarkRequest(args)
+
+	case "dlvSeekBookmark":
+		var args DlvSeekBookmarkArguments
+		if err := unmarshalLaunchAttachArgs(arguments, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.onDlvSeekBookmarkRequest(args)
+
+	default:
+		return nil, fmt.Errorf("unknown custom request %q", command)
+	}
+}
@@ -0,0 +1,26 @@
+package dap
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOnCustomRequestUnknownCommand(t *testing.T) {
+	s := &Server{}
+	if _, err := s.onCustomRequest("bogus", json.RawMessage("{}")); err == nil {
+		t.Fatal("expected an error for an unknown custom request")
+	}
+}
+
+func TestOnCustomRequestDecodesDlvSeekBookmarkArgs(t *testing.T) {
+	s := &Server{}
+
+	// A malformed arguments payload should be rejected by the decoder
+	// before reaching onDlvSeekBookmarkRequest; this confirms
+	// onCustomRequest actually routes "dlvSeekBookmark" to that decode
+	// step instead of the "unknown request" default branch.
+	_, err := s.onCustomRequest("dlvSeekBookmark", json.RawMessage(`{"event":"not-a-number"}`))
+	if err == nil {
+		t.Fatal("expected an error for malformed dlvSeekBookmark arguments")
+	}
+}
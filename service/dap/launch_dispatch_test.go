@@ -0,0 +1,27 @@
+package dap
+
+import "testing"
+
+func TestOnLaunchRequestDispatchesRecordAndReplay(t *testing.T) {
+	s := &Server{}
+
+	// With Program unset, launchRecordAndReplay itself should reject the
+	// config; this confirms onLaunchRequest actually reaches that handler
+	// for "recordAndReplay" instead of falling through to the default
+	// "unhandled mode" branch.
+	_, _, err := s.onLaunchRequest(LaunchConfig{Mode: "recordAndReplay"}, nil)
+	if err == nil {
+		t.Fatal("expected an error for a recordAndReplay config missing Program")
+	}
+	if got, want := err.Error(), "the program attribute is missing in debug configuration"; got != want {
+		t.Errorf("err = %q, want %q", got, want)
+	}
+}
+
+func TestOnLaunchRequestUnknownMode(t *testing.T) {
+	s := &Server{}
+	_, _, err := s.onLaunchRequest(LaunchConfig{Mode: "bogus"}, nil)
+	if err == nil {
+		t.Fatal("expected an error for an unknown launch mode")
+	}
+}
@@ -0,0 +1,62 @@
+package dap
+
+import (
+	"fmt"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/pkg/proc/gdbserial"
+)
+
+// launchRecordAndReplay implements the "recordAndReplay" launch mode. It is
+// invoked from the "recordAndReplay" case of onLaunchRequest's mode switch.
+//
+// If config.TracePackPath is set, it unpacks that trace pack and replays it
+// directly. Otherwise it records config.Program (with config.Args, Cwd and
+// Env, and the rr options from config.Rr) with gdbserial.RecordAndReplay.
+// If config.ExportTracePath is set, the recorded trace is packed to that
+// path with gdbserial.PackTrace before this function returns, so the
+// session can be handed off to another machine.
+func (s *Server) launchRecordAndReplay(config LaunchConfig, debugInfoDirs []string) (*proc.TargetGroup, string, error) {
+	if config.TracePackPath != "" {
+		tracedir, err := gdbserial.UnpackTrace(config.TracePackPath)
+		if err != nil {
+			return nil, "", fmt.Errorf("could not unpack trace pack %s: %v", config.TracePackPath, err)
+		}
+		tgt, err := gdbserial.Replay(tracedir, false, true, debugInfoDirs)
+		if err != nil {
+			return nil, "", err
+		}
+		return tgt, tracedir, nil
+	}
+
+	if config.Program == "" {
+		return nil, "", fmt.Errorf("the program attribute is missing in debug configuration")
+	}
+
+	opts := gdbserial.RecordOptions{
+		Chaos:                   config.Rr.Chaos,
+		NumCores:                config.Rr.NumCores,
+		DisableCPUIDFeaturesExt: config.Rr.DisableCPUIDFeaturesExt,
+		TraceDir:                config.Rr.TraceDir,
+		Env:                     config.Env,
+	}
+
+	cmd := append([]string{config.Program}, config.Args...)
+	tgt, tracedir, err := gdbserial.RecordAndReplay(cmd, config.Cwd, false, debugInfoDirs, proc.Redirect{}, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if config.ExportTracePath != "" {
+		if err := gdbserial.PackTrace(tracedir, config.ExportTracePath); err != nil {
+			// Match the error contract of every other path in this
+			// function (and of the sibling debug/test/exec/replay/core
+			// launch modes): a non-nil error means no live target was
+			// left behind for the caller to manage.
+			tgt.Detach(true)
+			return nil, "", fmt.Errorf("recording succeeded but exporting the trace to %s failed: %v", config.ExportTracePath, err)
+		}
+	}
+
+	return tgt, tracedir, nil
+}
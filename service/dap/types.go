@@ -29,6 +29,13 @@ import (
 //	   Required args: traceDirPath
 //	   Optional args: args
 //
+//	-- "recordAndReplay" - records the specified program with mozilla rr, then replays
+//	   the resulting trace. Mozilla rr must be installed.
+//
+//	   Required args: program (a pre-built binary; not needed when tracePackPath is set)
+//	   Optional args with default: cwd
+//	   Optional args: args, env, rr, tracePackPath, exportTracePath
+//
 //	-- "core" - examines a core dump (only supports linux and windows core dumps).
 //
 //	   Required args: program, coreFilePath
@@ -38,7 +45,7 @@ import (
 // all the required/optional fields mentioned above.
 func isValidLaunchMode(mode string) bool {
 	switch mode {
-	case "exec", "debug", "test", "replay", "core":
+	case "exec", "debug", "test", "replay", "recordAndReplay", "core":
 		return true
 	}
 	return false
@@ -126,6 +133,19 @@ type LaunchConfig struct {
 	// This is required for "core" mode but unused in other modes.
 	CoreFilePath string `json:"coreFilePath,omitempty"`
 
+	// TracePackPath is the path to a portable rr trace pack (a directory
+	// or tarball produced by `rr pack`) to unpack and replay.
+	// When set in "recordAndReplay" mode, Delve unpacks it and replays it
+	// directly instead of recording Program from scratch.
+	// Unused in other modes.
+	TracePackPath string `json:"tracePackPath,omitempty"`
+
+	// ExportTracePath is the path Delve should pack the recorded rr trace
+	// to (via `rr pack`) once recording finishes, so it can be copied to
+	// another machine and replayed there with TracePackPath.
+	// Only used in "recordAndReplay" mode.
+	ExportTracePath string `json:"exportTracePath,omitempty"`
+
 	// DlvCwd is the new working directory for Delve server.
 	// If specified, the server will change its working
 	// directory to the specified directory using os.Chdir.
@@ -149,9 +169,36 @@ type LaunchConfig struct {
 	Env map[string]*string `json:"env,omitempty"`
 
 	OutputModel string `json:"outputModel,omitempty"`
+
+	// Rr holds options forwarded to `rr record` when Delve records a
+	// program before debugging it (in "recordAndReplay" mode).
+	// Unused in modes that do not record.
+	Rr RrConfig `json:"rr,omitempty"`
+
 	LaunchAttachCommonConfig
 }
 
+// RrConfig holds the subset of rr's record-side flags that are useful for
+// reproducing concurrency bugs.
+type RrConfig struct {
+	// Chaos enables rr's chaos mode (--chaos), randomizing scheduling
+	// decisions to make flaky goroutine races easier to reproduce.
+	Chaos bool `json:"chaos,omitempty"`
+
+	// NumCores limits the number of cores rr's chaos mode scheduler may
+	// use (--num-cores=N).
+	NumCores int `json:"numCores,omitempty"`
+
+	// DisableCPUIDFeaturesExt disables rr's extended CPUID feature set
+	// (--disable-cpuid-features-ext), which is necessary to record on
+	// some CPUs.
+	DisableCPUIDFeaturesExt bool `json:"disableCpuidFeaturesExt,omitempty"`
+
+	// TraceDir, if set, is the directory rr should write the recording to
+	// (_RR_TRACE_DIR), instead of rr's default trace directory.
+	TraceDir string `json:"traceDir,omitempty"`
+}
+
 // LaunchAttachCommonConfig is the attributes common in both launch/attach requests.
 type LaunchAttachCommonConfig struct {
 	// Automatically stop program after launch or attach.
@@ -221,6 +268,33 @@ func (m *SubstitutePath) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// DlvBookmarkArguments are the arguments for the custom "dlvBookmark"
+// request, which reports the current position in an rr replay so that an
+// editor extension can save it for later.
+type DlvBookmarkArguments struct {
+}
+
+// DlvBookmarkResponseBody is the body of the response to the custom
+// "dlvBookmark" request.
+type DlvBookmarkResponseBody struct {
+	// Event is rr's event count at the bookmarked position.
+	Event uint64 `json:"event"`
+	// Ticks is rr's tick count at the bookmarked position.
+	Ticks uint64 `json:"ticks"`
+	// ThreadID is the thread that was current when the bookmark was taken.
+	ThreadID int `json:"threadId"`
+}
+
+// DlvSeekBookmarkArguments are the arguments for the custom
+// "dlvSeekBookmark" request, which moves the replay to a previously saved
+// bookmark.
+type DlvSeekBookmarkArguments struct {
+	// Event is the rr event count to seek to.
+	Event uint64 `json:"event"`
+	// Ticks is the rr tick count to seek to.
+	Ticks uint64 `json:"ticks"`
+}
+
 // AttachConfig is the collection of attach request attributes recognized by DAP implementation.
 type AttachConfig struct {
 	// Acceptable values are: